@@ -5,28 +5,49 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"os/exec"
 	"sync"
 	"time"
 
-	"github.com/getsentry/raven-go"
+	"github.com/robfig/cron/v3"
+	"github.com/unit9/gron/state"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v2"
 )
 
 const VERSION = "v0.2"
 
+// scheduleParser accepts standard 5-field cron expressions, 6-field ones with
+// a leading seconds field, and @every/@hourly-style descriptors.
+var scheduleParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
 var debug = flag.Bool("d", false, "enable debug logging")
 var version = flag.Bool("v", false, "show version and exit")
 
 var rawlog *zap.Logger
 var log *zap.SugaredLogger
-var sentry *raven.Client
+var notifier Notifier = &MultiNotifier{}
+var jobState *state.State // nil unless state_file is configured
 
 type Cron struct {
 	CronJobs []*CronJob     `yaml:"cron"`
 	Report   *ReportOptions `yaml:"report"`
+	Http     *HTTPOptions   `yaml:"http"`
+
+	// Default IANA zone (e.g. "America/New_York") jobs in this file are
+	// scheduled in, overridable per job. Defaults to the host's local zone.
+	Timezone string `yaml:"timezone"`
+
+	// Where to persist per-job last-successful-run timestamps, for catch_up.
+	StateFile string `yaml:"state_file"`
+}
+
+type HTTPOptions struct {
+	Listen string `yaml:"listen"`
 }
 
 type CronJob struct {
@@ -35,25 +56,104 @@ type CronJob struct {
 	Command     string `yaml:"command"`
 	Pwd         string `yaml:"pwd"`
 
-	// When? How often?
+	// When? How often? Evaluated in Timezone (or the file's default zone).
+	// Note Hour is a wall-clock hour: around a DST transition it can be
+	// skipped entirely (spring forward) or match twice (fall back).
 	Minute  *int          `yaml:"minute"`
 	Hour    *int          `yaml:"hour"`
 	Day     *int          `yaml:"day"`
 	Weekday *time.Weekday `yaml:"weekday"`
 
+	// IANA zone override for this job, e.g. "America/New_York". Falls back
+	// to the file-level Cron.Timezone, then the host's local zone.
+	Timezone string `yaml:"timezone"`
+
+	// Or, a standard 5-field cron expression, a 6-field one with a leading
+	// seconds field, or an @every/@hourly style descriptor, parsed by
+	// robfig/cron. Mutually exclusive with the Minute/Hour/Day/Weekday
+	// fields above.
+	Schedule string `yaml:"schedule"`
+
 	// How long do we allow it to run?
 	Timeout *int `yaml:"timeout"`
 
 	// Do we prevent it from running again if it's already running?
 	Lock bool `yaml:"lock"`
 
+	// Suppress scheduling after repeated failures, with a growing cooldown.
+	Backoff *BackoffOptions `yaml:"backoff"`
+
+	// Stable identifier used as the state file key. Defaults to a hash of
+	// Description+Command if unset.
+	ID string `yaml:"id"`
+
+	// If true, and state_file is configured, run this job once at startup
+	// when its schedule would have fired since the last recorded run. Only
+	// applies to jobs with a Schedule expression.
+	CatchUp bool `yaml:"catch_up"`
+
 	// Private locking stuff
 	m sync.Mutex
 	x bool // must hold m to read/write
+
+	// Backoff bookkeeping. Both must hold m to read/write.
+	consecutiveFailures int
+	nextEligible        time.Time
+
+	// Parsed form of Schedule, set by Fix(). Nil for jobs using the
+	// legacy Minute/Hour/Day/Weekday fields.
+	sched cron.Schedule
+
+	// Resolved zone this job is scheduled in, set by Fix().
+	loc *time.Location
+
+	// Next time this job is due to be considered, and a ring buffer of its
+	// last few runs. Both must hold m to read/write.
+	nextRun time.Time
+	history []runRecord
 }
 
+// Keep just enough run history to answer "what happened last" over HTTP
+// without the log output growing unbounded.
+const maxRunHistory = 10
+const maxOutputTail = 4096
+
+type runRecord struct {
+	Start  time.Time
+	End    time.Time
+	Err    string // empty on success
+	Output string // tail of combined stdout+stderr
+}
+
+// ReportOptions configures the notification sinks a job failure or panic is
+// fanned out to. Any subset may be set; main builds a MultiNotifier from
+// whichever are present.
 type ReportOptions struct {
-	SentryDSN string `yaml:"SENTRY_DSN"`
+	Sentry  *SentryOptions  `yaml:"sentry"`
+	Webhook *WebhookOptions `yaml:"webhook"`
+	Slack   *SlackOptions   `yaml:"slack"`
+	SMTP    *SMTPOptions    `yaml:"smtp"`
+}
+
+type BackoffOptions struct {
+	MaxFailures  int     `yaml:"max_failures"`
+	InitialDelay int     `yaml:"initial_delay"` // seconds
+	MaxDelay     int     `yaml:"max_delay"`     // seconds
+	Multiplier   float64 `yaml:"multiplier"`
+}
+
+// delay returns the cooldown to apply for the n-th consecutive suppression,
+// n starting at 0 for the round that first reaches MaxFailures.
+func (b *BackoffOptions) delay(n int) time.Duration {
+	mult := b.Multiplier
+	if mult <= 0 {
+		mult = 2
+	}
+	d := float64(b.InitialDelay) * math.Pow(mult, float64(n))
+	if b.MaxDelay > 0 && d > float64(b.MaxDelay) {
+		d = float64(b.MaxDelay)
+	}
+	return time.Duration(d) * time.Second
 }
 
 func Usage() {
@@ -78,7 +178,13 @@ func LoadCron(fpath string) (*Cron, error) {
 }
 
 func (j *CronJob) IsItTime() bool {
-	now := time.Now()
+	return j.matchesAt(time.Now())
+}
+
+// matchesAt reports whether the legacy Minute/Hour/Day/Weekday fields match
+// at t, evaluated in j.loc.
+func (j *CronJob) matchesAt(t time.Time) bool {
+	now := t.In(j.loc)
 	if j.Minute != nil && *j.Minute != now.Minute() {
 		return false
 	}
@@ -111,24 +217,87 @@ func (j *CronJob) innerRun() {
 		log.Fatalw("stdinpipe", "error", err)
 	}
 	wp.Close()
+	start := time.Now()
 	out, err := cmd.CombinedOutput()
+	end := time.Now()
 	strOut := string(out)
 	log.Infow("completed", "job", j, "out", strOut, "err", err)
+	rec := runRecord{Start: start, End: end, Output: tail(strOut, maxOutputTail)}
 	if err != nil {
-		packet := raven.NewPacket(
-			fmt.Sprintf("Job failed: %s: %s", j.Description, err.Error()),
-		)
-		packet.Extra["err"] = err.Error()
-		packet.Extra["pwd"] = j.Pwd
-		packet.Extra["command"] = j.Command
-		packet.Extra["description"] = j.Description
-		packet.Extra["out"] = strOut
-		sentry.Capture(packet, nil)
+		rec.Err = err.Error()
+		notifier.JobFailed(j, strOut, err)
+	} else if jobState != nil {
+		if err := jobState.RecordRun(j.StateKey(), end); err != nil {
+			log.Errorw("state record", "error", err)
+		}
+	}
+	j.recordRun(rec)
+	j.recordOutcome(err, end)
+}
+
+// recordOutcome updates the backoff state after a run. A success resets the
+// failure streak; a failure that brings the streak to Backoff.MaxFailures or
+// beyond suppresses scheduling until a growing cooldown elapses.
+func (j *CronJob) recordOutcome(err error, now time.Time) {
+	if j.Backoff == nil {
+		return
+	}
+	j.m.Lock()
+	defer j.m.Unlock()
+	if err == nil {
+		j.consecutiveFailures = 0
+		j.nextEligible = time.Time{}
+		return
+	}
+	j.consecutiveFailures++
+	if j.consecutiveFailures >= j.Backoff.MaxFailures {
+		j.nextEligible = now.Add(j.Backoff.delay(j.consecutiveFailures - j.Backoff.MaxFailures))
+	}
+}
+
+func (j *CronJob) backoffActive(now time.Time) bool {
+	j.m.Lock()
+	defer j.m.Unlock()
+	return j.Backoff != nil && now.Before(j.nextEligible)
+}
+
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
 	}
+	return s[len(s)-n:]
+}
+
+func (j *CronJob) recordRun(r runRecord) {
+	j.m.Lock()
+	defer j.m.Unlock()
+	j.history = append(j.history, r)
+	if len(j.history) > maxRunHistory {
+		j.history = j.history[len(j.history)-maxRunHistory:]
+	}
+}
+
+func (j *CronJob) lastRun() (runRecord, bool) {
+	j.m.Lock()
+	defer j.m.Unlock()
+	if len(j.history) == 0 {
+		return runRecord{}, false
+	}
+	return j.history[len(j.history)-1], true
+}
+
+func (j *CronJob) isRunning() bool {
+	j.m.Lock()
+	defer j.m.Unlock()
+	return j.x
 }
 
 func (j *CronJob) Run() {
 	log.Debugw("considering", "job", j)
+	if j.backoffActive(time.Now()) {
+		log.Debugw("suppressed by backoff", "job", j)
+		return
+	}
 	if !j.Lock {
 		go j.innerRun()
 		return
@@ -150,16 +319,89 @@ func (j *CronJob) Run() {
 	}()
 }
 
-func (j *CronJob) Fix() {
+// Fix validates the job and resolves its derived state: the parsed cron
+// schedule (if any) and the timezone it's evaluated in, falling back to
+// defaultLoc (the file-level Cron.Timezone, or the host's local zone) when
+// the job doesn't set its own.
+func (j *CronJob) Fix(defaultLoc *time.Location) {
 	if j.Command == "" {
 		panic("No command specified: " + j.Description)
 	}
+	hasLegacy := j.Minute != nil || j.Hour != nil || j.Day != nil || j.Weekday != nil
+	if j.Schedule != "" {
+		if hasLegacy {
+			panic("Job specifies both schedule and minute/hour/day/weekday: " + j.Description)
+		}
+		sched, err := scheduleParser.Parse(j.Schedule)
+		if err != nil {
+			panic(fmt.Sprintf("bad schedule %q for %s: %s", j.Schedule, j.Description, err))
+		}
+		j.sched = sched
+	}
+	if j.Backoff != nil && j.Backoff.MaxFailures <= 0 {
+		panic(fmt.Sprintf("backoff.max_failures must be > 0 for %s", j.Description))
+	}
+	j.loc = defaultLoc
+	if j.Timezone != "" {
+		loc, err := time.LoadLocation(j.Timezone)
+		if err != nil {
+			panic(fmt.Sprintf("bad timezone %q for %s: %s", j.Timezone, j.Description, err))
+		}
+		j.loc = loc
+	}
 }
 
-func WaitUntilNextMinute() {
-	now := time.Now()
-	then := now.Add(time.Duration(time.Minute)).Truncate(time.Minute)
-	time.Sleep(then.Sub(now))
+// StateKey is the key this job's last-run time is persisted under.
+func (j *CronJob) StateKey() string {
+	if j.ID != "" {
+		return j.ID
+	}
+	return state.KeyFor(j.Description, j.Command)
+}
+
+// legacyNextRunHorizon bounds how far legacyNextRun will search for a match.
+// A day-of-month and weekday pinned together won't necessarily coincide
+// every year, but will within this horizon.
+const legacyNextRunHorizon = 5 * 366 * 24 * time.Hour
+
+// NextRun returns the next time this job should be considered for running,
+// strictly after `from`. Jobs with a Schedule delegate to the cron parser.
+// Legacy Minute/Hour/Day/Weekday jobs get a real next-match time from
+// legacyNextRun, falling back to "check again next minute" only if no match
+// turns up within the search horizon.
+func (j *CronJob) NextRun(from time.Time) time.Time {
+	if j.sched != nil {
+		return j.sched.Next(from.In(j.loc))
+	}
+	if next, ok := j.legacyNextRun(from); ok {
+		return next
+	}
+	return from.Add(time.Minute).Truncate(time.Minute)
+}
+
+// legacyNextRun steps forward minute-by-minute from `from` looking for the
+// next time matchesAt would return true, bounded to legacyNextRunHorizon.
+func (j *CronJob) legacyNextRun(from time.Time) (time.Time, bool) {
+	t := from.Truncate(time.Minute).Add(time.Minute)
+	for deadline := from.Add(legacyNextRunHorizon); t.Before(deadline); t = t.Add(time.Minute) {
+		if j.matchesAt(t) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// NextFireTime returns the last value computed by NextRun for this job.
+func (j *CronJob) NextFireTime() time.Time {
+	j.m.Lock()
+	defer j.m.Unlock()
+	return j.nextRun
+}
+
+func (j *CronJob) setNextFireTime(t time.Time) {
+	j.m.Lock()
+	defer j.m.Unlock()
+	j.nextRun = t
 }
 
 func InitLogging() {
@@ -173,9 +415,28 @@ func InitLogging() {
 		panic(err)
 	}
 	log = rawlog.Sugar()
-	sentry, err = raven.New(os.Getenv("SENTRY_DSN"))
-	if err != nil {
-		panic(err)
+}
+
+// catchUp runs each catch_up job once, synchronously, if its schedule would
+// have fired at least one more time between its last recorded run and now.
+// Jobs with no persisted history yet are left for the normal loop to pick up.
+// Only jobs with a Schedule expression are caught up; jobs using the legacy
+// Minute/Hour/Day/Weekday fields are skipped.
+func catchUp(jobs []*CronJob) {
+	now := time.Now()
+	for _, j := range jobs {
+		if !j.CatchUp || j.sched == nil {
+			continue
+		}
+		last, ok := jobState.LastRun(j.StateKey())
+		if !ok {
+			continue
+		}
+		if j.sched.Next(last.In(j.loc)).After(now) {
+			continue
+		}
+		log.Infow("catch-up", "job", j, "last_run", last)
+		j.innerRun()
 	}
 }
 
@@ -187,30 +448,91 @@ func main() {
 		os.Exit(0)
 	}
 	jobs := []*CronJob{}
+	var httpOpts *HTTPOptions
+	var reportOpts *ReportOptions
+	var stateFile string
 	InitLogging()
 	for _, arg := range flag.Args() {
 		c, err := LoadCron(arg)
 		if err != nil {
 			log.Fatalw("load", "error", err)
 		}
-		if c.Report != nil && c.Report.SentryDSN != "" {
-			sentry.SetDSN(c.Report.SentryDSN)
+		if c.Report != nil {
+			reportOpts = c.Report
+		}
+		if c.Http != nil {
+			httpOpts = c.Http
+		}
+		if c.StateFile != "" {
+			stateFile = c.StateFile
+		}
+		defaultLoc := time.Local
+		if c.Timezone != "" {
+			loc, err := time.LoadLocation(c.Timezone)
+			if err != nil {
+				log.Fatalw("timezone", "error", err, "timezone", c.Timezone)
+			}
+			defaultLoc = loc
+		}
+		for _, j := range c.CronJobs {
+			j.Fix(defaultLoc)
 		}
 		jobs = append(jobs, c.CronJobs...)
 	}
 	log.Infow("hello", "jobs", jobs)
+
+	n, err := BuildNotifier(reportOpts)
+	if err != nil {
+		log.Fatalw("report", "error", err)
+	}
+	notifier = n
+
+	if httpOpts != nil && httpOpts.Listen != "" {
+		ServeHTTP(httpOpts.Listen, jobs)
+	}
+
+	if stateFile != "" {
+		st, err := state.Load(stateFile)
+		if err != nil {
+			log.Fatalw("state", "error", err)
+		}
+		jobState = st
+		catchUp(jobs)
+	}
+
+	now := time.Now()
 	for _, j := range jobs {
-		j.Fix()
+		j.setNextFireTime(j.NextRun(now))
 	}
 	for {
-		log.Debugw("tick", "now", time.Now())
+		now = time.Now()
+		soonest := now.Add(time.Minute).Truncate(time.Minute)
+		for _, j := range jobs {
+			if t := j.NextFireTime(); t.Before(soonest) {
+				soonest = t
+			}
+		}
+		if soonest.After(now) {
+			time.Sleep(soonest.Sub(now))
+			now = time.Now()
+		}
+		log.Debugw("tick", "now", now)
 		for _, j := range jobs {
-			sentry.CapturePanic(func() {
-				if j.IsItTime() {
-					j.Run()
+			if j.NextFireTime().After(now) {
+				continue
+			}
+			job := j
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						notifier.JobPanicked(job, r)
+					}
+				}()
+				if job.sched != nil || job.IsItTime() {
+					job.Run()
 				}
-			}, nil)
+			}()
+			job.setNextFireTime(job.NextRun(now))
 		}
-		WaitUntilNextMinute()
 	}
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffOptionsDelay(t *testing.T) {
+	b := &BackoffOptions{
+		MaxFailures:  3,
+		InitialDelay: 10,
+		MaxDelay:     120,
+		Multiplier:   2,
+	}
+	cases := []struct {
+		n    int
+		want time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 20 * time.Second},
+		{2, 40 * time.Second},
+		{3, 80 * time.Second},
+		{4, 120 * time.Second}, // capped by MaxDelay
+		{10, 120 * time.Second},
+	}
+	for _, c := range cases {
+		if got := b.delay(c.n); got != c.want {
+			t.Errorf("delay(%d) = %s, want %s", c.n, got, c.want)
+		}
+	}
+}
+
+func TestBackoffOptionsDelayDefaultMultiplier(t *testing.T) {
+	b := &BackoffOptions{InitialDelay: 5, MaxDelay: 1000}
+	if got, want := b.delay(0), 5*time.Second; got != want {
+		t.Errorf("delay(0) = %s, want %s", got, want)
+	}
+	if got, want := b.delay(1), 10*time.Second; got != want {
+		t.Errorf("delay(1) = %s, want %s", got, want)
+	}
+}
+
+func TestBackoffOptionsDelayNoMaxDelay(t *testing.T) {
+	b := &BackoffOptions{InitialDelay: 1, Multiplier: 10}
+	if got, want := b.delay(3), 1000*time.Second; got != want {
+		t.Errorf("delay(3) = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// JobStatus is the JSON shape returned by the /jobs endpoint.
+type JobStatus struct {
+	Description string    `json:"description"`
+	Command     string    `json:"command"`
+	Running     bool      `json:"running"`
+	NextRun     time.Time `json:"next_run"`
+	LastRun     time.Time `json:"last_run,omitempty"`
+	LastExit    string    `json:"last_exit,omitempty"`
+	LastOutput  string    `json:"last_output,omitempty"`
+}
+
+func jobStatuses(jobs []*CronJob) []JobStatus {
+	statuses := make([]JobStatus, 0, len(jobs))
+	for _, j := range jobs {
+		s := JobStatus{
+			Description: j.Description,
+			Command:     j.Command,
+			Running:     j.isRunning(),
+			NextRun:     j.NextFireTime(),
+		}
+		if rec, ok := j.lastRun(); ok {
+			s.LastRun = rec.Start
+			s.LastOutput = rec.Output
+			if rec.Err == "" {
+				s.LastExit = "ok"
+			} else {
+				s.LastExit = rec.Err
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// ServeHTTP starts the operator-facing HTTP API on a background goroutine
+// and returns immediately. A failure to bind is fatal, same as any other
+// startup error.
+func ServeHTTP(listen string, jobs []*CronJob) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(jobStatuses(jobs)); err != nil {
+			log.Errorw("http encode", "error", err)
+		}
+	})
+	go func() {
+		log.Infow("http listening", "addr", listen)
+		if err := http.ListenAndServe(listen, mux); err != nil {
+			log.Fatalw("http", "error", err)
+		}
+	}()
+}
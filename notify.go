@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+
+	"github.com/getsentry/raven-go"
+)
+
+// Notifier is told about job failures and panics from the scheduling loop.
+// The report: yaml block configures zero or more of these; main fans out to
+// all of them via a MultiNotifier.
+type Notifier interface {
+	JobFailed(job *CronJob, out string, err error)
+	JobPanicked(job *CronJob, recovered interface{})
+}
+
+// MultiNotifier fans a single notification out to every configured sink.
+type MultiNotifier struct {
+	Notifiers []Notifier
+}
+
+func (m *MultiNotifier) JobFailed(job *CronJob, out string, err error) {
+	for _, n := range m.Notifiers {
+		n.JobFailed(job, out, err)
+	}
+}
+
+func (m *MultiNotifier) JobPanicked(job *CronJob, recovered interface{}) {
+	for _, n := range m.Notifiers {
+		n.JobPanicked(job, recovered)
+	}
+}
+
+// BuildNotifier assembles a MultiNotifier from a report: config block. A nil
+// opts still yields a Sentry sink if the SENTRY_DSN env var is set, matching
+// gron's historical env-var-only configuration.
+func BuildNotifier(opts *ReportOptions) (*MultiNotifier, error) {
+	m := &MultiNotifier{}
+	dsn := os.Getenv("SENTRY_DSN")
+	if opts == nil {
+		opts = &ReportOptions{}
+	}
+	if opts.Sentry != nil && opts.Sentry.DSN != "" {
+		dsn = opts.Sentry.DSN
+	}
+	if dsn != "" {
+		client, err := raven.New(dsn)
+		if err != nil {
+			return nil, err
+		}
+		m.Notifiers = append(m.Notifiers, &SentryNotifier{client: client})
+	}
+	if opts.Webhook != nil && opts.Webhook.URL != "" {
+		m.Notifiers = append(m.Notifiers, &WebhookNotifier{opts: opts.Webhook})
+	}
+	if opts.Slack != nil && opts.Slack.WebhookURL != "" {
+		m.Notifiers = append(m.Notifiers, &SlackNotifier{opts: opts.Slack})
+	}
+	if opts.SMTP != nil && opts.SMTP.Host != "" {
+		m.Notifiers = append(m.Notifiers, &SMTPNotifier{opts: opts.SMTP})
+	}
+	return m, nil
+}
+
+type SentryOptions struct {
+	DSN string `yaml:"dsn"`
+}
+
+type SentryNotifier struct {
+	client *raven.Client
+}
+
+func (s *SentryNotifier) JobFailed(job *CronJob, out string, err error) {
+	packet := raven.NewPacket(
+		fmt.Sprintf("Job failed: %s: %s", job.Description, err.Error()),
+	)
+	packet.Extra["err"] = err.Error()
+	packet.Extra["pwd"] = job.Pwd
+	packet.Extra["command"] = job.Command
+	packet.Extra["description"] = job.Description
+	packet.Extra["out"] = out
+	s.client.Capture(packet, nil)
+}
+
+func (s *SentryNotifier) JobPanicked(job *CronJob, recovered interface{}) {
+	packet := raven.NewPacket(
+		fmt.Sprintf("Job panicked: %s: %v", job.Description, recovered),
+	)
+	packet.Extra["description"] = job.Description
+	packet.Extra["command"] = job.Command
+	s.client.Capture(packet, nil)
+}
+
+type WebhookOptions struct {
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+type WebhookNotifier struct {
+	opts *WebhookOptions
+}
+
+type webhookPayload struct {
+	Event       string `json:"event"`
+	Description string `json:"description"`
+	Command     string `json:"command"`
+	Error       string `json:"error,omitempty"`
+	Output      string `json:"output,omitempty"`
+}
+
+func (w *WebhookNotifier) send(p webhookPayload) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		log.Errorw("webhook marshal", "error", err)
+		return
+	}
+	method := w.opts.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, w.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		log.Errorw("webhook request", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Errorw("webhook send", "error", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func (w *WebhookNotifier) JobFailed(job *CronJob, out string, err error) {
+	w.send(webhookPayload{
+		Event: "job_failed", Description: job.Description, Command: job.Command,
+		Error: err.Error(), Output: out,
+	})
+}
+
+func (w *WebhookNotifier) JobPanicked(job *CronJob, recovered interface{}) {
+	w.send(webhookPayload{
+		Event: "job_panicked", Description: job.Description, Command: job.Command,
+		Error: fmt.Sprintf("%v", recovered),
+	})
+}
+
+type SlackOptions struct {
+	WebhookURL string `yaml:"webhook_url"`
+}
+
+type SlackNotifier struct {
+	opts *SlackOptions
+}
+
+func (s *SlackNotifier) post(text string) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		log.Errorw("slack marshal", "error", err)
+		return
+	}
+	resp, err := http.Post(s.opts.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Errorw("slack send", "error", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+func (s *SlackNotifier) JobFailed(job *CronJob, out string, err error) {
+	s.post(fmt.Sprintf("Job failed: %s: %s", job.Description, err.Error()))
+}
+
+func (s *SlackNotifier) JobPanicked(job *CronJob, recovered interface{}) {
+	s.post(fmt.Sprintf("Job panicked: %s: %v", job.Description, recovered))
+}
+
+type SMTPOptions struct {
+	Host string   `yaml:"host"`
+	From string   `yaml:"from"`
+	To   []string `yaml:"to"`
+}
+
+type SMTPNotifier struct {
+	opts *SMTPOptions
+}
+
+func (s *SMTPNotifier) send(subject, body string) {
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+		s.opts.From, strings.Join(s.opts.To, ", "), subject, body)
+	if err := smtp.SendMail(s.opts.Host, nil, s.opts.From, s.opts.To, []byte(msg)); err != nil {
+		log.Errorw("smtp send", "error", err)
+	}
+}
+
+func (s *SMTPNotifier) JobFailed(job *CronJob, out string, err error) {
+	s.send(
+		fmt.Sprintf("gron: job failed: %s", job.Description),
+		fmt.Sprintf("%s\n\n%s", err.Error(), out),
+	)
+}
+
+func (s *SMTPNotifier) JobPanicked(job *CronJob, recovered interface{}) {
+	s.send(
+		fmt.Sprintf("gron: job panicked: %s", job.Description),
+		fmt.Sprintf("%v", recovered),
+	)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixRejectsScheduleAndLegacyTogether(t *testing.T) {
+	minute := 5
+	j := &CronJob{
+		Description: "both",
+		Command:     "true",
+		Minute:      &minute,
+		Schedule:    "*/5 * * * *",
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Fix to panic when Schedule and Minute are both set")
+		}
+	}()
+	j.Fix(time.Local)
+}
+
+func TestFixRejectsBadSchedule(t *testing.T) {
+	j := &CronJob{Description: "bad", Command: "true", Schedule: "not a schedule"}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Fix to panic on an invalid schedule")
+		}
+	}()
+	j.Fix(time.Local)
+}
+
+func TestFixParsesScheduleVariants(t *testing.T) {
+	cases := []string{
+		"*/5 * * * *",   // standard 5-field
+		"*/5 * * * * *", // 6-field, leading seconds
+		"@every 1h",     // descriptor
+		"@hourly",       // descriptor
+	}
+	for _, sched := range cases {
+		j := &CronJob{Description: sched, Command: "true", Schedule: sched}
+		j.Fix(time.Local)
+		if j.sched == nil {
+			t.Errorf("Fix(%q) left j.sched nil", sched)
+		}
+	}
+}
+
+func TestNextRunUsesScheduleWhenSet(t *testing.T) {
+	j := &CronJob{Description: "every5", Command: "true", Schedule: "*/5 * * * *"}
+	j.Fix(time.UTC)
+
+	from := time.Date(2026, 7, 26, 10, 2, 0, 0, time.UTC)
+	want := time.Date(2026, 7, 26, 10, 5, 0, 0, time.UTC)
+	if got := j.NextRun(from); !got.Equal(want) {
+		t.Errorf("NextRun(%s) = %s, want %s", from, got, want)
+	}
+}
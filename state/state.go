@@ -0,0 +1,93 @@
+// Package state persists per-job last-successful-run timestamps to disk so
+// gron can resume correctly across restarts ("catch_up"/anacron mode).
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// JobState is the persisted record for a single job.
+type JobState struct {
+	LastRun time.Time `json:"last_run"`
+}
+
+// State is the in-memory, JSON-backed schema, keyed by a stable per-job ID
+// (see KeyFor). Safe for concurrent use.
+type State struct {
+	mu   sync.Mutex
+	path string
+	Jobs map[string]JobState `json:"jobs"`
+}
+
+// KeyFor derives a stable job ID from its description and command, for jobs
+// that don't set an explicit id.
+func KeyFor(description, command string) string {
+	h := sha256.Sum256([]byte(description + "\x00" + command))
+	return hex.EncodeToString(h[:])
+}
+
+// Load reads the state file at path, returning an empty State if it doesn't
+// exist yet.
+func Load(path string) (*State, error) {
+	s := &State{path: path, Jobs: map[string]JobState{}}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// LastRun returns the last recorded run time for jobID, if any.
+func (s *State) LastRun(jobID string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	js, ok := s.Jobs[jobID]
+	if !ok {
+		return time.Time{}, false
+	}
+	return js.LastRun, true
+}
+
+// RecordRun persists t as the last run time for jobID, atomically rewriting
+// the whole state file (tempfile + rename).
+func (s *State) RecordRun(jobID string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Jobs[jobID] = JobState{LastRun: t}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicWrite(s.path, data)
+}
+
+func atomicWrite(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".state-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,78 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyForIsStableAndDistinct(t *testing.T) {
+	a := KeyFor("backup db", "pg_dump foo")
+	b := KeyFor("backup db", "pg_dump foo")
+	if a != b {
+		t.Fatalf("KeyFor not stable: %q != %q", a, b)
+	}
+	if c := KeyFor("backup db", "pg_dump bar"); c == a {
+		t.Fatalf("KeyFor collided for different commands: %q", c)
+	}
+	if d := KeyFor("other job", "pg_dump foo"); d == a {
+		t.Fatalf("KeyFor collided for different descriptions: %q", d)
+	}
+}
+
+func TestLoadMissingFileIsEmpty(t *testing.T) {
+	s, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, ok := s.LastRun("anything"); ok {
+		t.Fatalf("expected no last run for a fresh state")
+	}
+}
+
+func TestRecordRunRoundTrips(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	want := time.Now().Truncate(time.Second)
+	if err := s.RecordRun("job-1", want); err != nil {
+		t.Fatalf("RecordRun: %v", err)
+	}
+
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load after RecordRun: %v", err)
+	}
+	got, ok := reloaded.LastRun("job-1")
+	if !ok {
+		t.Fatalf("expected a recorded last run for job-1")
+	}
+	if !got.Equal(want) {
+		t.Errorf("LastRun = %s, want %s", got, want)
+	}
+	if _, ok := reloaded.LastRun("job-2"); ok {
+		t.Errorf("unexpected last run for unrecorded job-2")
+	}
+}
+
+func TestRecordRunLeavesNoTempFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := s.RecordRun("job-1", time.Now()); err != nil {
+		t.Fatalf("RecordRun: %v", err)
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, ".state-*.tmp"))
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("leftover temp files after RecordRun: %v", matches)
+	}
+}
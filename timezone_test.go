@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixRejectsBadTimezone(t *testing.T) {
+	j := &CronJob{Description: "bad tz", Command: "true", Timezone: "Not/AZone"}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Fix to panic on an invalid timezone")
+		}
+	}()
+	j.Fix(time.Local)
+}
+
+func TestFixTimezoneFallsBackToFileDefault(t *testing.T) {
+	defaultLoc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %s", err)
+	}
+	j := &CronJob{Description: "no override", Command: "true"}
+	j.Fix(defaultLoc)
+	if j.loc != defaultLoc {
+		t.Errorf("loc = %v, want the file-level default %v", j.loc, defaultLoc)
+	}
+}
+
+func TestFixTimezoneJobOverridesFileDefault(t *testing.T) {
+	fileLoc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %s", err)
+	}
+	jobLoc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %s", err)
+	}
+	j := &CronJob{Description: "override", Command: "true", Timezone: "Asia/Tokyo"}
+	j.Fix(fileLoc)
+	if j.loc.String() != jobLoc.String() {
+		t.Errorf("loc = %v, want the job-level override %v", j.loc, jobLoc)
+	}
+}
+
+func TestFixTimezoneFallsBackToHostLocal(t *testing.T) {
+	j := &CronJob{Description: "host local", Command: "true"}
+	j.Fix(time.Local)
+	if j.loc != time.Local {
+		t.Errorf("loc = %v, want time.Local", j.loc)
+	}
+}
+
+func TestIsItTimeEvaluatesInJobZone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %s", err)
+	}
+	// 09:30 UTC is 18:30 in Tokyo.
+	hour := 18
+	j := &CronJob{Description: "tokyo hour", Command: "true", Hour: &hour, Timezone: "Asia/Tokyo"}
+	j.Fix(time.UTC)
+
+	at := time.Date(2026, 7, 26, 9, 30, 0, 0, time.UTC)
+	if !j.matchesAt(at) {
+		t.Errorf("expected match at %s (18:30 in %s)", at, tokyo)
+	}
+}